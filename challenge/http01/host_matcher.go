@@ -0,0 +1,96 @@
+package http01
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// trustedProxies holds the CIDR ranges a ProviderHandler will accept
+// X-Forwarded-Host / Forwarded headers from. Empty means no proxy is
+// trusted, i.e. only the request's own Host header is ever used.
+type trustedProxies struct {
+	nets []*net.IPNet
+}
+
+func (t *trustedProxies) add(cidr string) error {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+
+	t.nets = append(t.nets, ipNet)
+
+	return nil
+}
+
+func (t *trustedProxies) contains(ip net.IP) bool {
+	for _, ipNet := range t.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requestHost resolves the hostname the client intended to reach for r:
+// the `Host` header by default, or `X-Forwarded-Host` / the host portion of
+// `Forwarded` when r.RemoteAddr is a trusted proxy. The port, if any, is
+// stripped so it never takes part in the domain comparison.
+func requestHost(r *http.Request, proxies *trustedProxies) string {
+	host := r.Host
+
+	if proxies.isTrustedRequest(r) {
+		if fwd := r.Header.Get("X-Forwarded-Host"); fwd != "" {
+			host = fwd
+		} else if fwd := forwardedHost(r.Header.Get("Forwarded")); fwd != "" {
+			host = fwd
+		}
+	}
+
+	return stripPort(host)
+}
+
+func (t *trustedProxies) isTrustedRequest(r *http.Request) bool {
+	if t == nil || len(t.nets) == 0 {
+		return false
+	}
+
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(remoteIP)
+	if ip == nil {
+		return false
+	}
+
+	return t.contains(ip)
+}
+
+// forwardedHost extracts the `host` parameter from an RFC 7239 `Forwarded`
+// header value, e.g. `for=192.0.2.1;host=example.com;proto=https`.
+// Only the first forwarded-element is considered.
+func forwardedHost(header string) string {
+	element := strings.SplitN(header, ",", 2)[0]
+
+	for _, pair := range strings.Split(element, ";") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), "host") {
+			return strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		}
+	}
+
+	return ""
+}
+
+func stripPort(hostport string) string {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return hostport
+	}
+
+	return host
+}
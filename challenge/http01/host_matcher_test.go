@@ -0,0 +1,150 @@
+package http01
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestHost(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		host       string
+		remoteAddr string
+		headers    map[string]string
+		proxies    []string
+		expected   string
+	}{
+		{
+			desc:     "plain Host header",
+			host:     "example.com",
+			expected: "example.com",
+		},
+		{
+			desc:     "Host header with port is stripped",
+			host:     "example.com:8080",
+			expected: "example.com",
+		},
+		{
+			desc:       "X-Forwarded-Host ignored from an untrusted proxy",
+			host:       "example.com",
+			remoteAddr: "203.0.113.1:12345",
+			headers:    map[string]string{"X-Forwarded-Host": "attacker.example"},
+			expected:   "example.com",
+		},
+		{
+			desc:       "X-Forwarded-Host honored from a trusted proxy",
+			host:       "internal.example",
+			remoteAddr: "10.0.0.5:12345",
+			headers:    map[string]string{"X-Forwarded-Host": "example.com"},
+			proxies:    []string{"10.0.0.0/8"},
+			expected:   "example.com",
+		},
+		{
+			desc:       "Forwarded host honored from a trusted proxy",
+			host:       "internal.example",
+			remoteAddr: "10.0.0.5:12345",
+			headers:    map[string]string{"Forwarded": "for=192.0.2.1;host=example.com;proto=https"},
+			proxies:    []string{"10.0.0.0/8"},
+			expected:   "example.com",
+		},
+		{
+			desc:       "suffix spoofing does not fool the trusted proxy check",
+			host:       "example.com.evil.com",
+			remoteAddr: "203.0.113.1:12345",
+			proxies:    []string{"10.0.0.0/8"},
+			expected:   "example.com.evil.com",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			var proxies trustedProxies
+			for _, cidr := range test.proxies {
+				if err := proxies.add(cidr); err != nil {
+					t.Fatalf("add(%q): %v", cidr, err)
+				}
+			}
+
+			req := &http.Request{Host: test.host, Header: http.Header{}, RemoteAddr: test.remoteAddr}
+			for k, v := range test.headers {
+				req.Header.Set(k, v)
+			}
+
+			host := requestHost(req, &proxies)
+			if host != test.expected {
+				t.Errorf("requestHost() = %q, want %q", host, test.expected)
+			}
+		})
+	}
+}
+
+func TestProviderHandlerServeHTTPMatchesHostExactly(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		host        string
+		wantKeyAuth bool
+	}{
+		{desc: "exact match", host: "example.com", wantKeyAuth: true},
+		{desc: "case-insensitive match", host: "EXAMPLE.com", wantKeyAuth: true},
+		{desc: "subdomain is not a match", host: "sub.example.com", wantKeyAuth: false},
+		{desc: "lookalike suffix is not a match", host: "example.com.evil.com", wantKeyAuth: false},
+		{desc: "lookalike prefix is not a match", host: "evil-example.com", wantKeyAuth: false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			h := NewProviderHandler()
+			if err := h.Present("example.com", "tok", "tok.thumbprint"); err != nil {
+				t.Fatalf("Present: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, ChallengePath("tok"), nil)
+			req.Host = test.host
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			body := rec.Body.String()
+			gotKeyAuth := body == "tok.thumbprint"
+			if gotKeyAuth != test.wantKeyAuth {
+				t.Errorf("ServeHTTP with Host %q served key auth = %v, want %v (body %q)", test.host, gotKeyAuth, test.wantKeyAuth, body)
+			}
+		})
+	}
+}
+
+func TestTrustedProxiesIsTrustedRequest(t *testing.T) {
+	var proxies trustedProxies
+	if err := proxies.add("10.0.0.0/8"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	testCases := []struct {
+		desc       string
+		remoteAddr string
+		expected   bool
+	}{
+		{desc: "trusted proxy", remoteAddr: "10.1.2.3:5555", expected: true},
+		{desc: "untrusted proxy", remoteAddr: "203.0.113.1:5555", expected: false},
+		{desc: "no port in RemoteAddr", remoteAddr: "10.1.2.3", expected: true},
+		{desc: "unparseable RemoteAddr", remoteAddr: "not-an-ip", expected: false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			req := &http.Request{RemoteAddr: test.remoteAddr}
+			if trusted := proxies.isTrustedRequest(req); trusted != test.expected {
+				t.Errorf("isTrustedRequest(%q) = %v, want %v", test.remoteAddr, trusted, test.expected)
+			}
+		})
+	}
+
+	t.Run("no trusted proxies configured", func(t *testing.T) {
+		var empty trustedProxies
+		req := &http.Request{RemoteAddr: "10.1.2.3:5555"}
+		if empty.isTrustedRequest(req) {
+			t.Error("isTrustedRequest() = true with no configured proxies, want false")
+		}
+	})
+}
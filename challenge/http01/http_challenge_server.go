@@ -4,6 +4,7 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/go-acme/lego/log"
 )
@@ -16,14 +17,22 @@ type ProviderServer struct {
 	port     string
 	done     chan bool
 	listener net.Listener
-	mux      *http.ServeMux
+	handler  *ProviderHandler
+	stopOnce sync.Once
 }
 
 // NewProviderServer creates a new ProviderServer on the selected interface and port.
 // Setting iface and / or port to an empty string will make the server fall back to
 // the "any" interface and port 80 respectively.
+//
+// Unlike NewProviderHandler, this starts a listener of its own. CleanUp only
+// ever removes the one token it was called for - a single ProviderServer can
+// be presenting challenges for several domains at once during a SAN order,
+// and there is no way to tell from here whether another domain's Present is
+// still to come. The caller that knows the whole order is finished must call
+// Stop itself, or the listener leaks for the life of the process.
 func NewProviderServer(iface, port string) *ProviderServer {
-	s := &ProviderServer{iface: iface, port: port, mux: http.NewServeMux()}
+	s := &ProviderServer{iface: iface, port: port, handler: NewProviderHandler()}
 
 	var err error
 	s.listener, err = net.Listen("tcp", s.GetAddress())
@@ -33,21 +42,12 @@ func NewProviderServer(iface, port string) *ProviderServer {
 
 	s.done = make(chan bool)
 
-	httpServer := &http.Server{Handler: s.mux}
+	httpServer := &http.Server{Handler: s.handler}
 
 	// Once httpServer is shut down
 	// we don't want any lingering connections, so disable KeepAlives.
 	httpServer.SetKeepAlivesEnabled(false)
 
-	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Content-Type", "text/plain")
-		_, err := w.Write([]byte("OK"))
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-	})
-
 	go func() {
 		err = httpServer.Serve(s.listener)
 		if err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
@@ -59,52 +59,49 @@ func NewProviderServer(iface, port string) *ProviderServer {
 	return s
 }
 
-// Present starts a web server and makes the token available at `ChallengePath(token)` for web requests.
+// Present makes the token available at `ChallengePath(token)` for web requests.
 func (s *ProviderServer) Present(domain, token, keyAuth string) error {
 	if s.port == "" {
 		s.port = "80"
 	}
 
-	go s.serve(domain, token, keyAuth)
-	return nil
+	return s.handler.Present(domain, token, keyAuth)
 }
 
 func (s *ProviderServer) GetAddress() string {
 	return net.JoinHostPort(s.iface, s.port)
 }
 
-// CleanUp closes the HTTP server and removes the token from `ChallengePath(token)`
+// CleanUp removes the token from `ChallengePath(token)`.
+//
+// It does not shut down the listener: a single ProviderServer instance can
+// be presenting challenges for several domains at once during a SAN order,
+// and tearing down the listener on the first domain's CleanUp would break
+// the others. Call Stop once the whole order is finished.
 func (s *ProviderServer) CleanUp(domain, token, keyAuth string) error {
+	return s.handler.CleanUp(domain, token, keyAuth)
+}
+
+// Stop shuts down the HTTP server started by NewProviderServer. CleanUp never
+// stops it, so the caller must call Stop itself once the whole order is
+// finished. It is safe to call more than once.
+func (s *ProviderServer) Stop() error {
 	if s.listener == nil {
 		return nil
 	}
-	s.listener.Close()
-	<-s.done
+
+	s.stopOnce.Do(func() {
+		s.listener.Close()
+		<-s.done
+	})
+
 	return nil
 }
 
-func (s *ProviderServer) serve(domain, token, keyAuth string) {
-	path := ChallengePath(token)
-
-	// The handler validates the HOST header and request type.
-	// For validation it then writes the token the server returned with the challenge
-
-	s.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-		if strings.HasPrefix(r.Host, domain) && r.Method == http.MethodGet {
-			w.Header().Add("Content-Type", "text/plain")
-			_, err := w.Write([]byte(keyAuth))
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			log.Infof("[%s] Served key authentication", domain)
-		} else {
-			log.Warnf("Received request for domain %s with method %s but the domain did not match any challenge. Please ensure your are passing the HOST header properly.", r.Host, r.Method)
-			_, err := w.Write([]byte("TEST"))
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-		}
-	})
+// SetTrustedProxyCIDRs marks requests from any of cidrs as eligible to set
+// the effective Host via the X-Forwarded-Host / Forwarded headers, for use
+// behind a reverse proxy or load balancer that rewrites Host. No proxy is
+// trusted by default.
+func (s *ProviderServer) SetTrustedProxyCIDRs(cidrs ...string) error {
+	return s.handler.SetTrustedProxyCIDRs(cidrs...)
 }
@@ -0,0 +1,63 @@
+package http01
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+// TestProviderServerServesMultipleDomainsConcurrently guards against
+// cleaning up one domain's challenge tearing down the listener for
+// another domain still being presented in the same SAN order.
+func TestProviderServerServesMultipleDomainsConcurrently(t *testing.T) {
+	s := NewProviderServer("127.0.0.1", "0")
+	defer s.Stop()
+
+	addr := s.listener.Addr().String()
+
+	if err := s.Present("a.example.com", "tokA", "tokA.thumbprint"); err != nil {
+		t.Fatalf("Present(a): %v", err)
+	}
+	if err := s.Present("b.example.com", "tokB", "tokB.thumbprint"); err != nil {
+		t.Fatalf("Present(b): %v", err)
+	}
+
+	if err := s.CleanUp("a.example.com", "tokA", "tokA.thumbprint"); err != nil {
+		t.Fatalf("CleanUp(a): %v", err)
+	}
+
+	// domain B was never cleaned up - its challenge must still be reachable.
+	body, err := getKeyAuth(addr, "tokB", "b.example.com")
+	if err != nil {
+		t.Fatalf("GET for domain b after cleaning up domain a: %v", err)
+	}
+	if body != "tokB.thumbprint" {
+		t.Errorf("domain b key auth = %q, want %q", body, "tokB.thumbprint")
+	}
+
+	if err := s.CleanUp("b.example.com", "tokB", "tokB.thumbprint"); err != nil {
+		t.Fatalf("CleanUp(b): %v", err)
+	}
+}
+
+func getKeyAuth(addr, token, host string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s%s", addr, ChallengePath(token)), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Host = host
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
@@ -0,0 +1,107 @@
+package http01
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-acme/lego/log"
+)
+
+// challengeEntry is the keyAuth to serve for a domain's challenge token.
+type challengeEntry struct {
+	domain  string
+	keyAuth string
+}
+
+// ProviderHandler is an http.Handler serving `/.well-known/acme-challenge/*`
+// requests out of an in-memory, concurrency-safe registry of tokens. It has
+// no listener of its own, so it can be mounted onto any existing
+// http.ServeMux / middleware chain. The registry is what lets a single
+// instance serve concurrent challenges for many domains during a SAN order:
+// each domain gets its own map entry instead of a dedicated mux route, so
+// there is nothing to race or double-register.
+type ProviderHandler struct {
+	mu      sync.RWMutex
+	tokens  map[string]challengeEntry
+	proxies trustedProxies
+}
+
+// NewProviderHandler returns a handler that serves
+// `/.well-known/acme-challenge/*` requests for whatever tokens are currently
+// registered via its Present/CleanUp pair. Mount it on an existing
+// http.ServeMux / middleware chain to answer http-01 challenges without
+// giving up a dedicated listener, e.g. for a server already bound to port 80.
+func NewProviderHandler() *ProviderHandler {
+	return &ProviderHandler{tokens: map[string]challengeEntry{}}
+}
+
+// SetTrustedProxyCIDRs marks requests from any of cidrs as eligible to set
+// the effective Host via the X-Forwarded-Host / Forwarded headers, for use
+// behind a reverse proxy or load balancer that rewrites Host. No proxy is
+// trusted by default.
+func (h *ProviderHandler) SetTrustedProxyCIDRs(cidrs ...string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, cidr := range cidrs {
+		if err := h.proxies.add(cidr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Present registers keyAuth to be served at ChallengePath(token) for domain.
+func (h *ProviderHandler) Present(domain, token, keyAuth string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.tokens[ChallengePath(token)] = challengeEntry{domain: domain, keyAuth: keyAuth}
+
+	return nil
+}
+
+// CleanUp removes the entry registered for token, if any.
+func (h *ProviderHandler) CleanUp(domain, token, keyAuth string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.tokens, ChallengePath(token))
+
+	return nil
+}
+
+func (h *ProviderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	entry, ok := h.tokens[r.URL.Path]
+	proxies := h.proxies
+	h.mu.RUnlock()
+
+	if !ok {
+		w.Header().Add("Content-Type", "text/plain")
+		if _, err := w.Write([]byte("OK")); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// The handler resolves the effective Host and request type.
+	// For validation it then writes the token the server returned with the challenge.
+	host := requestHost(r, &proxies)
+	if strings.EqualFold(host, entry.domain) && r.Method == http.MethodGet {
+		w.Header().Add("Content-Type", "text/plain")
+		if _, err := w.Write([]byte(entry.keyAuth)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Infof("[%s] Served key authentication", entry.domain)
+		return
+	}
+
+	log.Warnf("Received request for domain %s with method %s but the domain did not match any challenge. Please ensure your are passing the HOST header properly.", r.Host, r.Method)
+	if _, err := w.Write([]byte("TEST")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
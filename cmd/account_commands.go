@@ -0,0 +1,27 @@
+package cmd
+
+import "github.com/urfave/cli"
+
+// createAccountCommands builds the `lego account` command group: export and
+// import, for migrating accounts between storage backends, seeding CI with a
+// pre-registered account, or disaster recovery.
+func createAccountCommands() cli.Command {
+	return cli.Command{
+		Name:  "account",
+		Usage: "Manage ACME accounts",
+		Subcommands: []cli.Command{
+			{
+				Name:   "export",
+				Usage:  "Export an account to a self-describing JSON document",
+				Action: accountExport,
+				Flags:  accountExportFlags(),
+			},
+			{
+				Name:   "import",
+				Usage:  "Import an account from a document produced by `account export`",
+				Action: accountImport,
+				Flags:  accountImportFlags(),
+			},
+		},
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"filippo.io/age"
+	"github.com/go-acme/lego/acme"
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/go-acme/lego/cmd/storage"
+	"github.com/go-acme/lego/registration"
+)
+
+// accountDocument is the self-describing, storage-agnostic serialization of
+// an account used by `lego account export` / `lego account import`. It
+// carries just enough of registration.Resource to re-hydrate an account
+// (the CA's URI for it, plus the contact/status the CA last reported), never
+// the whole ACME directory-specific payload.
+type accountDocument struct {
+	Email        string               `json:"email"`
+	Registration *registrationSummary `json:"registration"`
+	EABKeyID     string               `json:"eabKeyID,omitempty"`
+	PrivateKey   string               `json:"privateKey,omitempty"` // PEM
+}
+
+type registrationSummary struct {
+	URI     string   `json:"uri"`
+	Status  string   `json:"status"`
+	Contact []string `json:"contact,omitempty"`
+}
+
+func newAccountDocument(account *storage.Account, includeKey bool) (*accountDocument, error) {
+	doc := &accountDocument{Email: account.Email, EABKeyID: account.EABKeyID}
+
+	if reg := account.GetRegistration(); reg != nil {
+		doc.Registration = &registrationSummary{
+			URI:     reg.URI,
+			Status:  reg.Body.Status,
+			Contact: reg.Body.Contact,
+		}
+	}
+
+	if includeKey {
+		privateKey := account.GetPrivateKey()
+		if privateKey == nil {
+			return nil, errors.New("cmd: account has no private key to export")
+		}
+
+		doc.PrivateKey = string(pem.EncodeToMemory(certcrypto.PEMBlock(privateKey)))
+	}
+
+	return doc, nil
+}
+
+func (doc *accountDocument) toResource() *registration.Resource {
+	if doc.Registration == nil {
+		return nil
+	}
+
+	return &registration.Resource{
+		URI: doc.Registration.URI,
+		Body: acme.Account{
+			Status:  doc.Registration.Status,
+			Contact: doc.Registration.Contact,
+		},
+	}
+}
+
+func (doc *accountDocument) parsePrivateKey() (crypto.PrivateKey, error) {
+	if doc.PrivateKey == "" {
+		return nil, errors.New("cmd: document has no private key")
+	}
+
+	keyBlock, _ := pem.Decode([]byte(doc.PrivateKey))
+	if keyBlock == nil {
+		return nil, errors.New("cmd: unable to decode PEM block for private key")
+	}
+
+	switch keyBlock.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(keyBlock.Bytes)
+	default:
+		return nil, fmt.Errorf("cmd: unknown private key type %q", keyBlock.Type)
+	}
+}
+
+// marshalAccountDocument serializes doc to JSON, encrypting it for passphrase
+// with age's scrypt recipient when passphrase is non-empty.
+func marshalAccountDocument(doc *accountDocument, passphrase string) ([]byte, error) {
+	jsonBytes, err := json.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("cmd: unable to marshal account document: %w", err)
+	}
+
+	if passphrase == "" {
+		return jsonBytes, nil
+	}
+
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("cmd: unable to build passphrase recipient: %w", err)
+	}
+
+	var encrypted bytes.Buffer
+	w, err := age.Encrypt(&encrypted, recipient)
+	if err != nil {
+		return nil, fmt.Errorf("cmd: unable to encrypt account document: %w", err)
+	}
+
+	if _, err = w.Write(jsonBytes); err != nil {
+		return nil, fmt.Errorf("cmd: unable to encrypt account document: %w", err)
+	}
+
+	if err = w.Close(); err != nil {
+		return nil, fmt.Errorf("cmd: unable to encrypt account document: %w", err)
+	}
+
+	return encrypted.Bytes(), nil
+}
+
+// unmarshalAccountDocument parses raw into an accountDocument, decrypting it
+// first with passphrase if it looks like an age payload.
+func unmarshalAccountDocument(raw []byte, passphrase string) (*accountDocument, error) {
+	if bytes.HasPrefix(raw, []byte("age-encryption.org/")) {
+		if passphrase == "" {
+			return nil, errors.New("cmd: account document is encrypted, pass --passphrase to import it")
+		}
+
+		identity, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("cmd: unable to build passphrase identity: %w", err)
+		}
+
+		r, err := age.Decrypt(bytes.NewReader(raw), identity)
+		if err != nil {
+			return nil, fmt.Errorf("cmd: unable to decrypt account document: %w", err)
+		}
+
+		raw, err = ioutil.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("cmd: unable to decrypt account document: %w", err)
+		}
+	}
+
+	var doc accountDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("cmd: unable to parse account document: %w", err)
+	}
+
+	return &doc, nil
+}
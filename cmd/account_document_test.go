@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/go-acme/lego/acme"
+	"github.com/go-acme/lego/cmd/storage"
+	"github.com/go-acme/lego/registration"
+)
+
+func testAccount(t *testing.T) *storage.Account {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	account := &storage.Account{
+		Email:    "user@example.com",
+		EABKeyID: "eab-kid-1",
+		Registration: &registration.Resource{
+			URI: "https://acme.example.com/acme/acct/1",
+			Body: acme.Account{
+				Status:  "valid",
+				Contact: []string{"mailto:user@example.com"},
+			},
+		},
+	}
+	account.SetPrivateKey(key)
+
+	return account
+}
+
+func TestNewAccountDocumentRoundTripsThroughToResource(t *testing.T) {
+	account := testAccount(t)
+
+	doc, err := newAccountDocument(account, false)
+	if err != nil {
+		t.Fatalf("newAccountDocument: %v", err)
+	}
+
+	if doc.EABKeyID != account.EABKeyID {
+		t.Errorf("doc.EABKeyID = %q, want %q", doc.EABKeyID, account.EABKeyID)
+	}
+	if doc.PrivateKey != "" {
+		t.Error("doc.PrivateKey is set without includeKey, want empty")
+	}
+
+	reg := doc.toResource()
+	if reg == nil {
+		t.Fatal("toResource() = nil, want a populated resource")
+	}
+	if reg.URI != account.Registration.URI {
+		t.Errorf("toResource().URI = %q, want %q", reg.URI, account.Registration.URI)
+	}
+	if reg.Body.Status != account.Registration.Body.Status {
+		t.Errorf("toResource().Body.Status = %q, want %q", reg.Body.Status, account.Registration.Body.Status)
+	}
+}
+
+func TestNewAccountDocumentIncludeKey(t *testing.T) {
+	account := testAccount(t)
+
+	doc, err := newAccountDocument(account, true)
+	if err != nil {
+		t.Fatalf("newAccountDocument: %v", err)
+	}
+
+	privateKey, err := doc.parsePrivateKey()
+	if err != nil {
+		t.Fatalf("parsePrivateKey: %v", err)
+	}
+	if privateKey == nil {
+		t.Error("parsePrivateKey() = nil, want the account's private key")
+	}
+}
+
+func TestMarshalUnmarshalAccountDocumentPlaintext(t *testing.T) {
+	account := testAccount(t)
+
+	doc, err := newAccountDocument(account, true)
+	if err != nil {
+		t.Fatalf("newAccountDocument: %v", err)
+	}
+
+	raw, err := marshalAccountDocument(doc, "")
+	if err != nil {
+		t.Fatalf("marshalAccountDocument: %v", err)
+	}
+
+	got, err := unmarshalAccountDocument(raw, "")
+	if err != nil {
+		t.Fatalf("unmarshalAccountDocument: %v", err)
+	}
+
+	if got.Email != doc.Email || got.EABKeyID != doc.EABKeyID || got.PrivateKey != doc.PrivateKey {
+		t.Errorf("unmarshalAccountDocument() = %+v, want %+v", got, doc)
+	}
+}
+
+func TestMarshalUnmarshalAccountDocumentEncrypted(t *testing.T) {
+	account := testAccount(t)
+
+	doc, err := newAccountDocument(account, true)
+	if err != nil {
+		t.Fatalf("newAccountDocument: %v", err)
+	}
+
+	const passphrase = "correct horse battery staple"
+
+	raw, err := marshalAccountDocument(doc, passphrase)
+	if err != nil {
+		t.Fatalf("marshalAccountDocument: %v", err)
+	}
+
+	if _, err = unmarshalAccountDocument(raw, ""); err == nil {
+		t.Error("unmarshalAccountDocument() with no passphrase succeeded, want an error")
+	}
+
+	got, err := unmarshalAccountDocument(raw, passphrase)
+	if err != nil {
+		t.Fatalf("unmarshalAccountDocument: %v", err)
+	}
+
+	if got.Email != doc.Email || got.PrivateKey != doc.PrivateKey {
+		t.Errorf("unmarshalAccountDocument() = %+v, want %+v", got, doc)
+	}
+
+	if _, err = unmarshalAccountDocument(raw, "wrong passphrase"); err == nil {
+		t.Error("unmarshalAccountDocument() with the wrong passphrase succeeded, want an error")
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/urfave/cli"
+)
+
+// accountExport implements `lego account export`.
+//
+// It serializes the account's email, registration resource and (only with
+// --include-key) private key into a single JSON document, optionally
+// encrypted with --passphrase, so it can be fed to `lego account import`
+// against a different storage backend, used to seed CI with a pre-registered
+// account, or kept as a disaster-recovery copy.
+func accountExport(ctx *cli.Context) error {
+	email := getEmail(ctx)
+
+	storageBackend, err := NewStorage(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !storageBackend.HasAccount(email) {
+		return fmt.Errorf("cmd: no account found for %s", email)
+	}
+
+	privateKey, err := storageBackend.GetPrivateKey(email, getKeyType(ctx))
+	if err != nil {
+		return fmt.Errorf("cmd: unable to load private key for %s: %w", email, err)
+	}
+
+	account, err := storageBackend.LoadAccount(email, privateKey)
+	if err != nil {
+		return fmt.Errorf("cmd: unable to load account for %s: %w", email, err)
+	}
+
+	includeKey := ctx.Bool("include-key")
+	doc, err := newAccountDocument(account, includeKey)
+	if err != nil {
+		return err
+	}
+
+	raw, err := marshalAccountDocument(doc, ctx.String("passphrase"))
+	if err != nil {
+		return err
+	}
+
+	output := ctx.String("output")
+	if output == "" || output == "-" {
+		_, err = os.Stdout.Write(raw)
+		return err
+	}
+
+	return ioutil.WriteFile(output, raw, 0600)
+}
+
+func accountExportFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:  "include-key",
+			Usage: "Include the account private key in the export. Without this flag the key is never emitted.",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "Encrypt the export with this passphrase.",
+		},
+		cli.StringFlag{
+			Name:  "output",
+			Usage: "Write the export to this file instead of stdout.",
+		},
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/go-acme/lego/cmd/storage"
+	"github.com/go-acme/lego/lego"
+	"github.com/go-acme/lego/log"
+	"github.com/go-acme/lego/registration"
+	"github.com/urfave/cli"
+)
+
+var errAccountExists = errors.New("cmd: account already exists, use --force to overwrite")
+
+// accountImport implements `lego account import`.
+//
+// It re-hydrates an account document produced by `lego account export` into
+// whatever storage backend is currently configured (`--storage`), which is
+// what makes migrating between backends, or seeding a CI system with a
+// pre-registered account, possible. After loading, it calls
+// client.Registration.ResolveAccountByKey() to make sure the account is
+// still live at the CA before persisting anything.
+func accountImport(ctx *cli.Context) error {
+	input := ctx.String("input")
+	if input == "" {
+		return errors.New("cmd: --input is required")
+	}
+
+	raw, err := ioutil.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("cmd: unable to read %s: %w", input, err)
+	}
+
+	doc, err := unmarshalAccountDocument(raw, ctx.String("passphrase"))
+	if err != nil {
+		return err
+	}
+
+	if doc.Email == "" {
+		return errors.New("cmd: account document has no email")
+	}
+
+	privateKey, err := doc.parsePrivateKey()
+	if err != nil {
+		return fmt.Errorf("cmd: account document has no usable private key: %w", err)
+	}
+
+	storageBackend, err := NewStorage(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !ctx.Bool("force") {
+		if _, err = storageBackend.LoadAccount(doc.Email, privateKey); err == nil {
+			return errAccountExists
+		}
+	}
+
+	account := &storage.Account{Email: doc.Email, Registration: doc.toResource(), EABKeyID: doc.EABKeyID}
+	account.SetPrivateKey(privateKey)
+
+	reg, err := resolveAccountByKey(ctx, account)
+	if err != nil {
+		return fmt.Errorf("cmd: account %s is no longer valid at the CA: %w", doc.Email, err)
+	}
+	account.Registration = reg
+
+	if err = storageBackend.SaveAccount(account); err != nil {
+		return fmt.Errorf("cmd: unable to save account %s: %w", doc.Email, err)
+	}
+
+	if err = storageBackend.SavePrivateKey(doc.Email, privateKey); err != nil {
+		return fmt.Errorf("cmd: unable to save private key for %s: %w", doc.Email, err)
+	}
+
+	log.Infof("Imported account for %s", doc.Email)
+
+	return nil
+}
+
+func resolveAccountByKey(ctx *cli.Context, account *storage.Account) (*registration.Resource, error) {
+	config := lego.NewConfig(account)
+	config.CADirURL = ctx.GlobalString("server")
+	config.UserAgent = fmt.Sprintf("lego-cli/%s", ctx.App.Version)
+
+	client, err := lego.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Registration.ResolveAccountByKey()
+}
+
+func accountImportFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "input",
+			Usage: "Read the account document from this file.",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "Decrypt the import with this passphrase.",
+		},
+		cli.BoolFlag{
+			Name:  "force",
+			Usage: "Overwrite an existing account in the configured storage backend.",
+		},
+	}
+}
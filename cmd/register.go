@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-acme/lego/cmd/storage"
+	"github.com/go-acme/lego/lego"
+	"github.com/go-acme/lego/log"
+	"github.com/go-acme/lego/registration"
+	"github.com/urfave/cli"
+)
+
+// register agrees to the CA's terms of service and creates the ACME account
+// for client's user, binding it to an External Account (EAB) when the CA
+// requires one.
+//
+// EAB credentials may come from `--eab-kid`/`--eab-hmac`, or from a kid
+// already persisted on account (set on a prior registration), so that
+// re-registering an existing account stays idempotent.
+func register(ctx *cli.Context, client *lego.Client, account *storage.Account) (*registration.Resource, error) {
+	accepted := ctx.GlobalBool("accept-tos")
+	if !accepted {
+		return nil, errors.New("cmd: you must accept the CA's Terms of Service, use --accept-tos")
+	}
+
+	kid := ctx.GlobalString("eab-kid")
+	hmacEncoded := ctx.GlobalString("eab-hmac")
+
+	if kid == "" {
+		kid = account.EABKeyID
+	}
+
+	if kid == "" && hmacEncoded == "" {
+		reg, err := client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: accepted})
+		if err != nil {
+			return nil, fmt.Errorf("cmd: could not register account for %s: %w", account.Email, err)
+		}
+
+		return reg, nil
+	}
+
+	if kid == "" || hmacEncoded == "" {
+		return nil, errors.New("cmd: both --eab-kid and --eab-hmac are required to use External Account Binding")
+	}
+
+	log.Infof("Registering account for %s using External Account Binding (kid %s)", account.Email, kid)
+
+	reg, err := client.Registration.RegisterWithExternalAccountBinding(registration.RegisterEABOptions{
+		TermsOfServiceAgreed: accepted,
+		Kid:                  kid,
+		HmacEncoded:          hmacEncoded,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cmd: could not register account for %s using External Account Binding: %w", account.Email, err)
+	}
+
+	account.EABKeyID = kid
+
+	return reg, nil
+}
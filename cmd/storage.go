@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/go-acme/lego/cmd/storage"
+	"github.com/urfave/cli"
+
+	// Backends register themselves with the storage package on import.
+	// Third parties can add their own backend by importing this package and
+	// their own, and calling storage.Register from an init() in their package.
+	_ "github.com/go-acme/lego/cmd/storage/consul"
+	_ "github.com/go-acme/lego/cmd/storage/etcd"
+	_ "github.com/go-acme/lego/cmd/storage/file"
+	_ "github.com/go-acme/lego/cmd/storage/s3"
+	_ "github.com/go-acme/lego/cmd/storage/vault"
+)
+
+const defaultStorageBackend = "file"
+
+// NewStorage builds the Storage backend selected by `--storage` (defaulting
+// to the local filesystem backend), passing the CLI context through so the
+// chosen backend can read its own flags.
+func NewStorage(ctx *cli.Context) (storage.Storage, error) {
+	backend := ctx.GlobalString("storage")
+	if backend == "" {
+		backend = defaultStorageBackend
+	}
+
+	s, err := storage.New(backend, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+
+	return s, nil
+}
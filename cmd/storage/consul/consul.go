@@ -0,0 +1,212 @@
+// Package consul implements a HashiCorp Consul KV storage backend.
+package consul
+
+import (
+	"crypto"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/go-acme/lego/certificate"
+	"github.com/go-acme/lego/cmd/storage"
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	storage.Register("consul", New)
+}
+
+const keyPrefix = "lego/"
+
+// Storage is the Consul KV implementation of storage.Storage.
+// Keys are namespaced under keyPrefix so the backend can share a KV store
+// with other applications.
+type Storage struct {
+	kv *consulapi.KV
+}
+
+// New creates the Consul storage backend, using the standard CONSUL_HTTP_ADDR
+// and related environment variables, optionally overridden by `--consul-addr`.
+func New(ctx *cli.Context) (storage.Storage, error) {
+	config := consulapi.DefaultConfig()
+	if addr := ctx.GlobalString("consul-addr"); addr != "" {
+		config.Address = addr
+	}
+
+	client, err := consulapi.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("consul: unable to create client: %w", err)
+	}
+
+	return &Storage{kv: client.KV()}, nil
+}
+
+func (s *Storage) SaveResource(certRes *certificate.Resource) error {
+	if err := s.WriteFile(certRes.Domain, ".crt", certRes.Certificate); err != nil {
+		return fmt.Errorf("consul: unable to save certificate for domain %s: %w", certRes.Domain, err)
+	}
+
+	if certRes.IssuerCertificate != nil {
+		if err := s.WriteFile(certRes.Domain, ".issuer.crt", certRes.IssuerCertificate); err != nil {
+			return fmt.Errorf("consul: unable to save issuer certificate for domain %s: %w", certRes.Domain, err)
+		}
+	}
+
+	if certRes.PrivateKey != nil {
+		if err := s.WriteFile(certRes.Domain, ".key", certRes.PrivateKey); err != nil {
+			return fmt.Errorf("consul: unable to save private key for domain %s: %w", certRes.Domain, err)
+		}
+	}
+
+	jsonBytes, err := json.MarshalIndent(certRes, "", "\t")
+	if err != nil {
+		return fmt.Errorf("consul: unable to marshal certificate resource for domain %s: %w", certRes.Domain, err)
+	}
+
+	return s.WriteFile(certRes.Domain, ".json", jsonBytes)
+}
+
+func (s *Storage) ReadResource(domain string) (certificate.Resource, error) {
+	raw, err := s.ReadFile(domain, ".json")
+	if err != nil {
+		return certificate.Resource{}, err
+	}
+
+	var resource certificate.Resource
+	if err = json.Unmarshal(raw, &resource); err != nil {
+		return certificate.Resource{}, fmt.Errorf("consul: error while unmarshaling the meta data for domain %s: %w", domain, err)
+	}
+
+	return resource, nil
+}
+
+func (s *Storage) ExistsFile(domain, extension string) bool {
+	pair, _, err := s.kv.Get(s.fileKey(domain, extension), nil)
+	return err == nil && pair != nil
+}
+
+func (s *Storage) ReadFile(domain, extension string) ([]byte, error) {
+	pair, _, err := s.kv.Get(s.fileKey(domain, extension), nil)
+	if err != nil {
+		return nil, fmt.Errorf("consul: unable to read %s%s: %w", domain, extension, err)
+	}
+	if pair == nil {
+		return nil, fmt.Errorf("consul: no value for %s%s", domain, extension)
+	}
+
+	return pair.Value, nil
+}
+
+func (s *Storage) WriteFile(domain, extension string, data []byte) error {
+	return s.put(s.fileKey(domain, extension), data)
+}
+
+func (s *Storage) MoveToArchive(domain string) error {
+	pairs, _, err := s.kv.List(keyPrefix+"certs/"+domain+"/", nil)
+	if err != nil {
+		return fmt.Errorf("consul: unable to list keys for domain %s: %w", domain, err)
+	}
+
+	for _, pair := range pairs {
+		_, err = s.kv.Put(&consulapi.KVPair{Key: keyPrefix + "archives/" + pair.Key[len(keyPrefix):], Value: pair.Value}, nil)
+		if err != nil {
+			return fmt.Errorf("consul: unable to archive %s: %w", pair.Key, err)
+		}
+
+		if _, err = s.kv.Delete(pair.Key, nil); err != nil {
+			return fmt.Errorf("consul: unable to delete %s after archiving: %w", pair.Key, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) HasAccount(userID string) bool {
+	pair, _, err := s.kv.Get(keyPrefix+"accounts/"+userID+"/account.json", nil)
+	return err == nil && pair != nil
+}
+
+func (s *Storage) SaveAccount(account *storage.Account) error {
+	jsonBytes, err := json.MarshalIndent(account, "", "\t")
+	if err != nil {
+		return fmt.Errorf("consul: unable to marshal account %s: %w", account.Email, err)
+	}
+
+	return s.put(keyPrefix+"accounts/"+account.Email+"/account.json", jsonBytes)
+}
+
+func (s *Storage) LoadAccount(userID string, privateKey crypto.PrivateKey) (*storage.Account, error) {
+	pair, _, err := s.kv.Get(keyPrefix+"accounts/"+userID+"/account.json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("consul: could not load account for %s: %w", userID, err)
+	}
+	if pair == nil {
+		return nil, fmt.Errorf("consul: no account found for %s", userID)
+	}
+
+	var account storage.Account
+	if err = json.Unmarshal(pair.Value, &account); err != nil {
+		return nil, fmt.Errorf("consul: could not parse account for %s: %w", userID, err)
+	}
+
+	account.SetPrivateKey(privateKey)
+
+	return &account, nil
+}
+
+func (s *Storage) GetPrivateKey(userID string, keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
+	key := keyPrefix + "accounts/" + userID + "/keys/" + userID + ".key"
+
+	pair, _, err := s.kv.Get(key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("consul: unable to read private key for account %s: %w", userID, err)
+	}
+
+	if pair == nil {
+		privateKey, errGen := certcrypto.GeneratePrivateKey(keyType)
+		if errGen != nil {
+			return nil, fmt.Errorf("consul: could not generate private key for account %s: %w", userID, errGen)
+		}
+
+		if err = s.SavePrivateKey(userID, privateKey); err != nil {
+			return nil, err
+		}
+
+		return privateKey, nil
+	}
+
+	privateKey, err := storage.ParsePEMPrivateKey(pair.Value)
+	if err != nil {
+		return nil, fmt.Errorf("consul: could not parse private key for account %s: %w", userID, err)
+	}
+
+	return privateKey, nil
+}
+
+// SavePrivateKey persists privateKey verbatim as userID's account key,
+// overwriting any key already stored for it.
+func (s *Storage) SavePrivateKey(userID string, privateKey crypto.PrivateKey) error {
+	key := keyPrefix + "accounts/" + userID + "/keys/" + userID + ".key"
+
+	if err := s.put(key, pem.EncodeToMemory(certcrypto.PEMBlock(privateKey))); err != nil {
+		return fmt.Errorf("consul: could not save private key for account %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+// fileKey builds the KV key for domain's file of the given extension.
+// extension carries its leading dot (e.g. ".crt"), as passed by callers of
+// Storage.WriteFile/ReadFile/ExistsFile, so it is stripped here to keep keys
+// free of stray dots.
+func (s *Storage) fileKey(domain, extension string) string {
+	return keyPrefix + "certs/" + domain + "/" + strings.TrimPrefix(extension, ".")
+}
+
+func (s *Storage) put(key string, value []byte) error {
+	_, err := s.kv.Put(&consulapi.KVPair{Key: key, Value: value}, nil)
+	return err
+}
@@ -0,0 +1,228 @@
+// Package etcd implements an etcd v3 storage backend.
+package etcd
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/go-acme/lego/certificate"
+	"github.com/go-acme/lego/cmd/storage"
+	"github.com/urfave/cli"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func init() {
+	storage.Register("etcd", New)
+}
+
+const keyPrefix = "/lego/"
+
+const dialTimeout = 5 * time.Second
+
+// Storage is the etcd v3 implementation of storage.Storage.
+type Storage struct {
+	client *clientv3.Client
+}
+
+// New creates the etcd storage backend. Endpoints are taken from the
+// comma-separated `--etcd-endpoints` flag, defaulting to "127.0.0.1:2379".
+func New(ctx *cli.Context) (storage.Storage, error) {
+	endpoints := strings.Split(ctx.GlobalString("etcd-endpoints"), ",")
+	if len(endpoints) == 0 || endpoints[0] == "" {
+		endpoints = []string{"127.0.0.1:2379"}
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: dialTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("etcd: unable to create client: %w", err)
+	}
+
+	return &Storage{client: client}, nil
+}
+
+func (s *Storage) SaveResource(certRes *certificate.Resource) error {
+	if err := s.WriteFile(certRes.Domain, ".crt", certRes.Certificate); err != nil {
+		return fmt.Errorf("etcd: unable to save certificate for domain %s: %w", certRes.Domain, err)
+	}
+
+	if certRes.IssuerCertificate != nil {
+		if err := s.WriteFile(certRes.Domain, ".issuer.crt", certRes.IssuerCertificate); err != nil {
+			return fmt.Errorf("etcd: unable to save issuer certificate for domain %s: %w", certRes.Domain, err)
+		}
+	}
+
+	if certRes.PrivateKey != nil {
+		if err := s.WriteFile(certRes.Domain, ".key", certRes.PrivateKey); err != nil {
+			return fmt.Errorf("etcd: unable to save private key for domain %s: %w", certRes.Domain, err)
+		}
+	}
+
+	jsonBytes, err := json.MarshalIndent(certRes, "", "\t")
+	if err != nil {
+		return fmt.Errorf("etcd: unable to marshal certificate resource for domain %s: %w", certRes.Domain, err)
+	}
+
+	return s.WriteFile(certRes.Domain, ".json", jsonBytes)
+}
+
+func (s *Storage) ReadResource(domain string) (certificate.Resource, error) {
+	raw, err := s.ReadFile(domain, ".json")
+	if err != nil {
+		return certificate.Resource{}, err
+	}
+
+	var resource certificate.Resource
+	if err = json.Unmarshal(raw, &resource); err != nil {
+		return certificate.Resource{}, fmt.Errorf("etcd: error while unmarshaling the meta data for domain %s: %w", domain, err)
+	}
+
+	return resource, nil
+}
+
+func (s *Storage) ExistsFile(domain, extension string) bool {
+	resp, err := s.get(s.fileKey(domain, extension))
+	return err == nil && len(resp.Kvs) > 0
+}
+
+func (s *Storage) ReadFile(domain, extension string) ([]byte, error) {
+	resp, err := s.get(s.fileKey(domain, extension))
+	if err != nil {
+		return nil, fmt.Errorf("etcd: unable to read %s%s: %w", domain, extension, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("etcd: no value for %s%s", domain, extension)
+	}
+
+	return resp.Kvs[0].Value, nil
+}
+
+func (s *Storage) WriteFile(domain, extension string, data []byte) error {
+	return s.put(s.fileKey(domain, extension), data)
+}
+
+func (s *Storage) MoveToArchive(domain string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	prefix := keyPrefix + "certs/" + domain + "/"
+
+	resp, err := s.client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("etcd: unable to list keys for domain %s: %w", domain, err)
+	}
+
+	for _, kv := range resp.Kvs {
+		archiveKey := keyPrefix + "archives/" + domain + "/" + strings.TrimPrefix(string(kv.Key), prefix)
+		if err = s.put(archiveKey, kv.Value); err != nil {
+			return fmt.Errorf("etcd: unable to archive %s: %w", kv.Key, err)
+		}
+
+		if _, err = s.client.Delete(ctx, string(kv.Key)); err != nil {
+			return fmt.Errorf("etcd: unable to delete %s after archiving: %w", kv.Key, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) HasAccount(userID string) bool {
+	resp, err := s.get(keyPrefix + "accounts/" + userID + "/account.json")
+	return err == nil && len(resp.Kvs) > 0
+}
+
+func (s *Storage) SaveAccount(account *storage.Account) error {
+	jsonBytes, err := json.MarshalIndent(account, "", "\t")
+	if err != nil {
+		return fmt.Errorf("etcd: unable to marshal account %s: %w", account.Email, err)
+	}
+
+	return s.put(keyPrefix+"accounts/"+account.Email+"/account.json", jsonBytes)
+}
+
+func (s *Storage) LoadAccount(userID string, privateKey crypto.PrivateKey) (*storage.Account, error) {
+	resp, err := s.get(keyPrefix + "accounts/" + userID + "/account.json")
+	if err != nil {
+		return nil, fmt.Errorf("etcd: could not load account for %s: %w", userID, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("etcd: no account found for %s", userID)
+	}
+
+	var account storage.Account
+	if err = json.Unmarshal(resp.Kvs[0].Value, &account); err != nil {
+		return nil, fmt.Errorf("etcd: could not parse account for %s: %w", userID, err)
+	}
+
+	account.SetPrivateKey(privateKey)
+
+	return &account, nil
+}
+
+func (s *Storage) GetPrivateKey(userID string, keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
+	key := keyPrefix + "accounts/" + userID + "/keys/" + userID + ".key"
+
+	resp, err := s.get(key)
+	if err != nil {
+		return nil, fmt.Errorf("etcd: unable to read private key for account %s: %w", userID, err)
+	}
+
+	if len(resp.Kvs) == 0 {
+		privateKey, errGen := certcrypto.GeneratePrivateKey(keyType)
+		if errGen != nil {
+			return nil, fmt.Errorf("etcd: could not generate private key for account %s: %w", userID, errGen)
+		}
+
+		if err = s.SavePrivateKey(userID, privateKey); err != nil {
+			return nil, err
+		}
+
+		return privateKey, nil
+	}
+
+	privateKey, err := storage.ParsePEMPrivateKey(resp.Kvs[0].Value)
+	if err != nil {
+		return nil, fmt.Errorf("etcd: could not parse private key for account %s: %w", userID, err)
+	}
+
+	return privateKey, nil
+}
+
+// SavePrivateKey persists privateKey verbatim as userID's account key,
+// overwriting any key already stored for it.
+func (s *Storage) SavePrivateKey(userID string, privateKey crypto.PrivateKey) error {
+	key := keyPrefix + "accounts/" + userID + "/keys/" + userID + ".key"
+
+	if err := s.put(key, pem.EncodeToMemory(certcrypto.PEMBlock(privateKey))); err != nil {
+		return fmt.Errorf("etcd: could not save private key for account %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) fileKey(domain, extension string) string {
+	return keyPrefix + "certs/" + domain + "/" + strings.TrimPrefix(extension, ".")
+}
+
+func (s *Storage) put(key string, value []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	_, err := s.client.Put(ctx, key, string(value))
+	return err
+}
+
+func (s *Storage) get(key string) (*clientv3.GetResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	return s.client.Get(ctx, key)
+}
@@ -0,0 +1,245 @@
+// Package file implements the local filesystem storage backend.
+// It is the default backend used by the CLI when `--storage` is not set.
+package file
+
+import (
+	"crypto"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/go-acme/lego/certificate"
+	"github.com/go-acme/lego/cmd/storage"
+	"github.com/go-acme/lego/log"
+	"github.com/urfave/cli"
+)
+
+const filePerm os.FileMode = 0600
+
+func init() {
+	storage.Register("file", New)
+}
+
+// Storage is the local filesystem implementation of storage.Storage.
+//
+// layout:
+//
+//	./.lego/certificates/{domain}.{crt,key,issuer.crt,json}
+//	./.lego/accounts/{server}/{email}/account.json
+//	./.lego/accounts/{server}/{email}/keys/{email}.key
+type Storage struct {
+	rootPath     string
+	accountsPath string
+	archivePath  string
+	server       string
+}
+
+var serverPathReplacer = strings.NewReplacer("://", "_", ":", "_", "/", "_")
+
+// New creates the local filesystem storage backend, rooted at `--path`.
+func New(ctx *cli.Context) (storage.Storage, error) {
+	rootPath := ctx.GlobalString("path")
+	if rootPath == "" {
+		return nil, errors.New("file: --path must not be empty")
+	}
+
+	s := &Storage{
+		rootPath:     filepath.Join(rootPath, "certificates"),
+		accountsPath: filepath.Join(rootPath, "accounts"),
+		archivePath:  filepath.Join(rootPath, "archives"),
+		server:       serverPathReplacer.Replace(ctx.GlobalString("server")),
+	}
+
+	for _, dir := range []string{s.rootPath, s.accountsPath, s.archivePath} {
+		if err := createNonExistingFolder(dir); err != nil {
+			return nil, fmt.Errorf("file: could not create directory %q: %w", dir, err)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Storage) SaveResource(certRes *certificate.Resource) error {
+	err := s.WriteFile(certRes.Domain, ".crt", certRes.Certificate)
+	if err != nil {
+		return fmt.Errorf("file: unable to save certificate for domain %s: %w", certRes.Domain, err)
+	}
+
+	if certRes.IssuerCertificate != nil {
+		err = s.WriteFile(certRes.Domain, ".issuer.crt", certRes.IssuerCertificate)
+		if err != nil {
+			return fmt.Errorf("file: unable to save issuer certificate for domain %s: %w", certRes.Domain, err)
+		}
+	}
+
+	if certRes.PrivateKey != nil {
+		err = s.WriteFile(certRes.Domain, ".key", certRes.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("file: unable to save private key for domain %s: %w", certRes.Domain, err)
+		}
+	}
+
+	jsonBytes, err := json.MarshalIndent(certRes, "", "\t")
+	if err != nil {
+		return fmt.Errorf("file: unable to marshal certificate resource for domain %s: %w", certRes.Domain, err)
+	}
+
+	return s.WriteFile(certRes.Domain, ".json", jsonBytes)
+}
+
+func (s *Storage) ReadResource(domain string) (certificate.Resource, error) {
+	raw, err := s.ReadFile(domain, ".json")
+	if err != nil {
+		return certificate.Resource{}, fmt.Errorf("file: error while loading the meta data for domain %s: %w", domain, err)
+	}
+
+	var resource certificate.Resource
+	if err = json.Unmarshal(raw, &resource); err != nil {
+		return certificate.Resource{}, fmt.Errorf("file: error while unmarshaling the meta data for domain %s: %w", domain, err)
+	}
+
+	return resource, nil
+}
+
+func (s *Storage) ExistsFile(domain, extension string) bool {
+	_, err := os.Stat(s.domainFilePath(domain, extension))
+	return err == nil
+}
+
+func (s *Storage) ReadFile(domain, extension string) ([]byte, error) {
+	return ioutil.ReadFile(s.domainFilePath(domain, extension))
+}
+
+func (s *Storage) WriteFile(domain, extension string, data []byte) error {
+	return ioutil.WriteFile(s.domainFilePath(domain, extension), data, filePerm)
+}
+
+func (s *Storage) MoveToArchive(domain string) error {
+	matches, err := filepath.Glob(filepath.Join(s.rootPath, domain+".*"))
+	if err != nil {
+		return err
+	}
+
+	for _, oldFile := range matches {
+		date := strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "_")
+		newFile := filepath.Join(s.archivePath, date+"."+filepath.Base(oldFile))
+
+		err = os.Rename(oldFile, newFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) HasAccount(userID string) bool {
+	_, err := os.Stat(filepath.Join(s.userAccountPath(userID), "account.json"))
+	return err == nil
+}
+
+func (s *Storage) SaveAccount(account *storage.Account) error {
+	jsonBytes, err := json.MarshalIndent(account, "", "\t")
+	if err != nil {
+		return fmt.Errorf("file: unable to marshal account %s: %w", account.Email, err)
+	}
+
+	accountPath := s.userAccountPath(account.Email)
+	if err = createNonExistingFolder(accountPath); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(accountPath, "account.json"), jsonBytes, filePerm)
+}
+
+func (s *Storage) LoadAccount(userID string, privateKey crypto.PrivateKey) (*storage.Account, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(s.userAccountPath(userID), "account.json"))
+	if err != nil {
+		return nil, fmt.Errorf("file: could not load account for %s: %w", userID, err)
+	}
+
+	var account storage.Account
+	if err = json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("file: could not parse account file for %s: %w", userID, err)
+	}
+
+	account.SetPrivateKey(privateKey)
+
+	return &account, nil
+}
+
+func (s *Storage) GetPrivateKey(userID string, keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
+	keyPath := s.userKeyPath(userID)
+
+	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
+		log.Printf("No key found for account %s. Generating a %s key.", userID, keyType)
+
+		privateKey, err := certcrypto.GeneratePrivateKey(keyType)
+		if err != nil {
+			return nil, fmt.Errorf("file: could not generate private key for account %s: %w", userID, err)
+		}
+
+		if err = s.SavePrivateKey(userID, privateKey); err != nil {
+			return nil, err
+		}
+
+		return privateKey, nil
+	}
+
+	raw, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("file: could not load private key for account %s: %w", userID, err)
+	}
+
+	privateKey, err := storage.ParsePEMPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("file: could not parse private key for account %s: %w", userID, err)
+	}
+
+	return privateKey, nil
+}
+
+// SavePrivateKey writes privateKey verbatim as userID's account key,
+// overwriting any key already on disk for it.
+func (s *Storage) SavePrivateKey(userID string, privateKey crypto.PrivateKey) error {
+	accountKeysPath := filepath.Join(s.userAccountPath(userID), "keys")
+	if err := createNonExistingFolder(accountKeysPath); err != nil {
+		return err
+	}
+
+	err := ioutil.WriteFile(s.userKeyPath(userID), pem.EncodeToMemory(certcrypto.PEMBlock(privateKey)), filePerm)
+	if err != nil {
+		return fmt.Errorf("file: could not save private key for account %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) domainFilePath(domain, extension string) string {
+	return filepath.Join(s.rootPath, domain+extension)
+}
+
+func (s *Storage) userKeyPath(userID string) string {
+	return filepath.Join(s.userAccountPath(userID), "keys", userID+".key")
+}
+
+func (s *Storage) userAccountPath(userID string) string {
+	return filepath.Join(s.accountsPath, s.server, userID)
+}
+
+func createNonExistingFolder(path string) error {
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return os.MkdirAll(path, 0700)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
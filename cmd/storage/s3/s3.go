@@ -0,0 +1,252 @@
+// Package s3 implements a storage backend for S3-compatible object storage
+// (AWS S3, MinIO, and similar).
+package s3
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/go-acme/lego/certificate"
+	"github.com/go-acme/lego/cmd/storage"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	storage.Register("s3", New)
+}
+
+const keyPrefix = "lego/"
+
+// Storage is the S3-compatible object storage implementation of storage.Storage.
+type Storage struct {
+	client *s3.S3
+	bucket string
+}
+
+// New creates the S3 storage backend. `--s3-bucket` is required; `--s3-endpoint`
+// and `--s3-region` may be used to target S3-compatible services such as MinIO.
+func New(ctx *cli.Context) (storage.Storage, error) {
+	bucket := ctx.GlobalString("s3-bucket")
+	if bucket == "" {
+		return nil, errors.New("s3: --s3-bucket must not be empty")
+	}
+
+	config := aws.NewConfig()
+	if endpoint := ctx.GlobalString("s3-endpoint"); endpoint != "" {
+		config = config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+	if region := ctx.GlobalString("s3-region"); region != "" {
+		config = config.WithRegion(region)
+	}
+
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, fmt.Errorf("s3: unable to create session: %w", err)
+	}
+
+	return &Storage{client: s3.New(sess), bucket: bucket}, nil
+}
+
+func (s *Storage) SaveResource(certRes *certificate.Resource) error {
+	if err := s.WriteFile(certRes.Domain, ".crt", certRes.Certificate); err != nil {
+		return fmt.Errorf("s3: unable to save certificate for domain %s: %w", certRes.Domain, err)
+	}
+
+	if certRes.IssuerCertificate != nil {
+		if err := s.WriteFile(certRes.Domain, ".issuer.crt", certRes.IssuerCertificate); err != nil {
+			return fmt.Errorf("s3: unable to save issuer certificate for domain %s: %w", certRes.Domain, err)
+		}
+	}
+
+	if certRes.PrivateKey != nil {
+		if err := s.WriteFile(certRes.Domain, ".key", certRes.PrivateKey); err != nil {
+			return fmt.Errorf("s3: unable to save private key for domain %s: %w", certRes.Domain, err)
+		}
+	}
+
+	jsonBytes, err := json.MarshalIndent(certRes, "", "\t")
+	if err != nil {
+		return fmt.Errorf("s3: unable to marshal certificate resource for domain %s: %w", certRes.Domain, err)
+	}
+
+	return s.WriteFile(certRes.Domain, ".json", jsonBytes)
+}
+
+func (s *Storage) ReadResource(domain string) (certificate.Resource, error) {
+	raw, err := s.ReadFile(domain, ".json")
+	if err != nil {
+		return certificate.Resource{}, err
+	}
+
+	var resource certificate.Resource
+	if err = json.Unmarshal(raw, &resource); err != nil {
+		return certificate.Resource{}, fmt.Errorf("s3: error while unmarshaling the meta data for domain %s: %w", domain, err)
+	}
+
+	return resource, nil
+}
+
+func (s *Storage) ExistsFile(domain, extension string) bool {
+	_, err := s.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(domain, extension)),
+	})
+	return err == nil
+}
+
+func (s *Storage) ReadFile(domain, extension string) ([]byte, error) {
+	out, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(domain, extension)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3: unable to read %s%s: %w", domain, extension, err)
+	}
+	defer out.Body.Close()
+
+	return ioutil.ReadAll(out.Body)
+}
+
+func (s *Storage) WriteFile(domain, extension string, data []byte) error {
+	return s.putObject(s.objectKey(domain, extension), data)
+}
+
+func (s *Storage) MoveToArchive(domain string) error {
+	out, err := s.client.ListObjectsV2(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(keyPrefix + "certs/" + domain + "/"),
+	})
+	if err != nil {
+		return fmt.Errorf("s3: unable to list objects for domain %s: %w", domain, err)
+	}
+
+	for _, obj := range out.Contents {
+		_, err = s.client.CopyObject(&s3.CopyObjectInput{
+			Bucket:     aws.String(s.bucket),
+			CopySource: aws.String(s.bucket + "/" + *obj.Key),
+			Key:        aws.String(keyPrefix + "archives/" + domain + "/" + *obj.Key),
+		})
+		if err != nil {
+			return fmt.Errorf("s3: unable to archive %s: %w", *obj.Key, err)
+		}
+
+		_, err = s.client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: obj.Key})
+		if err != nil {
+			return fmt.Errorf("s3: unable to delete %s after archiving: %w", *obj.Key, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) HasAccount(userID string) bool {
+	_, err := s.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(keyPrefix + "accounts/" + userID + "/account.json"),
+	})
+	return err == nil
+}
+
+func (s *Storage) SaveAccount(account *storage.Account) error {
+	jsonBytes, err := json.MarshalIndent(account, "", "\t")
+	if err != nil {
+		return fmt.Errorf("s3: unable to marshal account %s: %w", account.Email, err)
+	}
+
+	return s.putObject(keyPrefix+"accounts/"+account.Email+"/account.json", jsonBytes)
+}
+
+func (s *Storage) LoadAccount(userID string, privateKey crypto.PrivateKey) (*storage.Account, error) {
+	out, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(keyPrefix + "accounts/" + userID + "/account.json"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3: could not load account for %s: %w", userID, err)
+	}
+	defer out.Body.Close()
+
+	raw, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("s3: could not read account for %s: %w", userID, err)
+	}
+
+	var account storage.Account
+	if err = json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("s3: could not parse account for %s: %w", userID, err)
+	}
+
+	account.SetPrivateKey(privateKey)
+
+	return &account, nil
+}
+
+func (s *Storage) GetPrivateKey(userID string, keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
+	key := keyPrefix + "accounts/" + userID + "/keys/" + userID + ".key"
+
+	out, err := s.client.GetObject(&s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != s3.ErrCodeNoSuchKey {
+			return nil, fmt.Errorf("s3: unable to read private key for account %s: %w", userID, err)
+		}
+
+		privateKey, errGen := certcrypto.GeneratePrivateKey(keyType)
+		if errGen != nil {
+			return nil, fmt.Errorf("s3: could not generate private key for account %s: %w", userID, errGen)
+		}
+
+		if errSave := s.SavePrivateKey(userID, privateKey); errSave != nil {
+			return nil, errSave
+		}
+
+		return privateKey, nil
+	}
+	defer out.Body.Close()
+
+	raw, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("s3: could not read private key for account %s: %w", userID, err)
+	}
+
+	privateKey, err := storage.ParsePEMPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("s3: could not parse private key for account %s: %w", userID, err)
+	}
+
+	return privateKey, nil
+}
+
+// SavePrivateKey persists privateKey verbatim as userID's account key,
+// overwriting any key already stored for it.
+func (s *Storage) SavePrivateKey(userID string, privateKey crypto.PrivateKey) error {
+	key := keyPrefix + "accounts/" + userID + "/keys/" + userID + ".key"
+
+	if err := s.putObject(key, pem.EncodeToMemory(certcrypto.PEMBlock(privateKey))); err != nil {
+		return fmt.Errorf("s3: could not save private key for account %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) objectKey(domain, extension string) string {
+	return keyPrefix + "certs/" + domain + "/" + domain + extension
+}
+
+func (s *Storage) putObject(key string, data []byte) error {
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
@@ -0,0 +1,137 @@
+// Package storage defines the pluggable storage abstraction used by the
+// lego CLI to persist ACME accounts and certificates, and the registry
+// through which concrete backends (file, vault, consul, etcd, s3, ...)
+// advertise themselves.
+package storage
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/go-acme/lego/certificate"
+	"github.com/go-acme/lego/registration"
+	"github.com/urfave/cli"
+)
+
+// Storage is implemented by every storage backend usable by the CLI.
+// A backend is responsible for persisting both certificate material and
+// account data; there is intentionally a single interface so a `--storage`
+// flag can select one implementation for both concerns.
+type Storage interface {
+	// SaveResource persists the certificate, key, issuer and metadata for certRes.Domain.
+	SaveResource(certRes *certificate.Resource) error
+	// ReadResource loads the previously saved metadata for domain.
+	ReadResource(domain string) (certificate.Resource, error)
+	// ExistsFile reports whether domain has a file with the given extension.
+	ExistsFile(domain, extension string) bool
+	// ReadFile loads the raw content of domain's file with the given extension.
+	ReadFile(domain, extension string) ([]byte, error)
+	// WriteFile persists raw content for domain under the given extension.
+	WriteFile(domain, extension string, data []byte) error
+	// MoveToArchive moves (or otherwise marks as superseded) all files for domain.
+	MoveToArchive(domain string) error
+
+	// HasAccount reports whether an account is persisted for userID, without
+	// creating or generating anything. Callers that must not mutate storage
+	// on a lookup miss (e.g. a read-only export) should check this instead of
+	// calling LoadAccount/GetPrivateKey and reacting to the error.
+	HasAccount(userID string) bool
+	// SaveAccount persists account.
+	SaveAccount(account *Account) error
+	// LoadAccount loads the account identified by userID, attaching privateKey to it.
+	LoadAccount(userID string, privateKey crypto.PrivateKey) (*Account, error)
+	// GetPrivateKey returns the account private key for userID, generating and
+	// persisting one of the given keyType if none exists yet.
+	GetPrivateKey(userID string, keyType certcrypto.KeyType) (crypto.PrivateKey, error)
+	// SavePrivateKey persists privateKey verbatim as userID's account key,
+	// overwriting whatever key (if any) is currently stored for it. Unlike
+	// GetPrivateKey, it never generates a key of its own; it exists so a
+	// caller that already has a specific key material — e.g. `account
+	// import` restoring an exported account — can make it the one GetPrivateKey
+	// and LoadAccount will subsequently return, instead of silently getting a
+	// freshly generated, unrelated key on next use.
+	SavePrivateKey(userID string, privateKey crypto.PrivateKey) error
+}
+
+// Account is the persisted representation of an ACME account.
+// It implements registration.User.
+type Account struct {
+	Email        string                 `json:"email"`
+	Registration *registration.Resource `json:"registration"`
+	// EABKeyID is the External Account Binding key identifier the account was
+	// registered with, if any. Persisting it lets re-registration against the
+	// same CA be idempotent without the caller passing `--eab-kid` again.
+	EABKeyID string `json:"eabKeyID,omitempty"`
+
+	key crypto.PrivateKey
+}
+
+// GetEmail implements registration.User.
+func (a *Account) GetEmail() string {
+	return a.Email
+}
+
+// GetPrivateKey implements registration.User.
+func (a *Account) GetPrivateKey() crypto.PrivateKey {
+	return a.key
+}
+
+// GetRegistration implements registration.User.
+func (a *Account) GetRegistration() *registration.Resource {
+	return a.Registration
+}
+
+// SetPrivateKey attaches a private key to the account.
+// Backends call this after loading the account's key separately from its metadata.
+func (a *Account) SetPrivateKey(key crypto.PrivateKey) {
+	a.key = key
+}
+
+// Factory builds a Storage instance from CLI flags.
+// It is called lazily, once, when the backend registered under its name is selected.
+type Factory func(ctx *cli.Context) (Storage, error)
+
+var registry = map[string]Factory{}
+
+// Register registers a Storage backend factory under name so it becomes
+// selectable via `--storage=name`. Backends call this from an init() function
+// in their own package; registering the same name twice panics, mirroring how
+// database/sql and similar registries in the standard library behave.
+func Register(name string, factory Factory) {
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("storage: backend %q already registered", name))
+	}
+	registry[name] = factory
+}
+
+// New builds the Storage backend named name, passing it ctx for its own flags.
+func New(name string, ctx *cli.Context) (Storage, error) {
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("storage: unknown backend %q", name)
+	}
+	return factory(ctx)
+}
+
+// ParsePEMPrivateKey decodes the PEM-encoded RSA or EC private key in raw.
+// Every backend stores account keys in this format, so they share this
+// instead of each re-implementing it.
+func ParsePEMPrivateKey(raw []byte) (crypto.PrivateKey, error) {
+	keyBlock, _ := pem.Decode(raw)
+	if keyBlock == nil {
+		return nil, errors.New("storage: unable to decode PEM block")
+	}
+
+	switch keyBlock.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(keyBlock.Bytes)
+	default:
+		return nil, fmt.Errorf("storage: unknown private key type %q", keyBlock.Type)
+	}
+}
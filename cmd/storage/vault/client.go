@@ -0,0 +1,50 @@
+package vault
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// vaultClient lazily builds and caches a Vault API client from the
+// environment (VAULT_ADDR, VAULT_TOKEN, ...), mirroring the official
+// Vault CLI's configuration conventions.
+type vaultClient struct {
+	address string
+
+	mu     sync.Mutex
+	client *api.Client
+}
+
+func newVaultClient(address string) *vaultClient {
+	return &vaultClient{address: address}
+}
+
+func (v *vaultClient) Get() (*api.Client, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.client != nil {
+		return v.client, nil
+	}
+
+	config := api.DefaultConfig()
+
+	if err := config.ReadEnvironment(); err != nil {
+		return nil, fmt.Errorf("vault: unable to read environment: %w", err)
+	}
+
+	if v.address != "" {
+		config.Address = v.address
+	}
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("vault: unable to create client: %w", err)
+	}
+
+	v.client = client
+
+	return client, nil
+}
@@ -0,0 +1,47 @@
+package vault
+
+import "github.com/urfave/cli"
+
+const (
+	defaultMountPath  = "secret"
+	defaultPathPrefix = "fabio"
+)
+
+// KV versions recognised by Config.KVVersion. 0 means "auto-detect".
+const (
+	kvVersionAuto = 0
+	kvVersionV1   = 1
+	kvVersionV2   = 2
+)
+
+// Config holds the parts of the Vault storage layout that differ between
+// installations: which secrets engine is mounted where, what prefix to
+// namespace lego's own entries under, and which KV protocol version that
+// mount speaks.
+type Config struct {
+	Address    string
+	MountPath  string
+	PathPrefix string
+	KVVersion  int
+}
+
+// NewConfig builds a Config from CLI flags, applying the same defaults
+// (mount "secret", prefix "fabio") the backend has always used so existing
+// deployments keep working without passing any new flags.
+func NewConfig(ctx *cli.Context) *Config {
+	cfg := &Config{
+		Address:    ctx.GlobalString("vault-addr"),
+		MountPath:  ctx.GlobalString("vault-mount"),
+		PathPrefix: ctx.GlobalString("vault-path-prefix"),
+		KVVersion:  ctx.GlobalInt("vault-kv-version"),
+	}
+
+	if cfg.MountPath == "" {
+		cfg.MountPath = defaultMountPath
+	}
+	if cfg.PathPrefix == "" {
+		cfg.PathPrefix = defaultPathPrefix
+	}
+
+	return cfg
+}
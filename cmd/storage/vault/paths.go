@@ -0,0 +1,87 @@
+package vault
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// kvPaths knows how to address entries under a KV v1 or v2 mount, and how to
+// wrap/unwrap the extra "data" envelope that KV v2 adds around values.
+//
+// v1 layout:   {mount}/{prefix}/{segment}/{key}
+// v2 layout:   {mount}/data/{prefix}/{segment}/{key}       (read, write, delete)
+//
+// Only the data path is built; {mount}/metadata/... is never addressed, so a
+// delete against a v2 mount removes the latest version only and keeps the
+// version history.
+type kvPaths struct {
+	cfg     *Config
+	version int
+}
+
+// newKVPaths resolves cfg.KVVersion, auto-detecting it against the live mount
+// at cfg.MountPath via `sys/mounts` when it is left unset.
+func newKVPaths(client *api.Client, cfg *Config) (*kvPaths, error) {
+	version := cfg.KVVersion
+	if version == kvVersionAuto {
+		detected, err := detectKVVersion(client, cfg.MountPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to detect KV version of mount %q: %w", cfg.MountPath, err)
+		}
+		version = detected
+	}
+
+	return &kvPaths{cfg: cfg, version: version}, nil
+}
+
+// detectKVVersion reads the mount's tuning options from sys/mounts and
+// inspects `options.version`, the same field the `vault secrets list -detailed`
+// CLI output is built from. Mounts with no version option are KV v1.
+func detectKVVersion(client *api.Client, mountPath string) (int, error) {
+	mounts, err := client.Sys().ListMounts()
+	if err != nil {
+		return 0, err
+	}
+
+	mount, ok := mounts[mountPath+"/"]
+	if !ok {
+		return 0, fmt.Errorf("no such secrets engine mounted at %q", mountPath)
+	}
+
+	if mount.Options["version"] == "2" {
+		return kvVersionV2, nil
+	}
+
+	return kvVersionV1, nil
+}
+
+func (p *kvPaths) dataPath(segment, key string) string {
+	if p.version == kvVersionV1 {
+		return fmt.Sprintf("%s/%s/%s/%s", p.cfg.MountPath, p.cfg.PathPrefix, segment, key)
+	}
+	return fmt.Sprintf("%s/data/%s/%s/%s", p.cfg.MountPath, p.cfg.PathPrefix, segment, key)
+}
+
+// wrap adds the KV v2 envelope around data, or returns it unchanged for v1.
+func (p *kvPaths) wrap(data map[string]interface{}) map[string]interface{} {
+	if p.version == kvVersionV1 {
+		return data
+	}
+	return map[string]interface{}{"data": data}
+}
+
+// unwrap strips the KV v2 envelope from a read response, or returns its data
+// unchanged for v1.
+func (p *kvPaths) unwrap(resp *api.Secret) (map[string]interface{}, error) {
+	if p.version == kvVersionV1 {
+		return resp.Data, nil
+	}
+
+	data, ok := resp.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("malformed KV v2 response at %v: missing \"data\"", resp)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,87 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func testConfig() *Config {
+	return &Config{MountPath: "secret", PathPrefix: "fabio"}
+}
+
+func TestKVPathsDataPath(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		version  int
+		expected string
+	}{
+		{desc: "v1 has no data segment", version: kvVersionV1, expected: "secret/fabio/certs/example.com"},
+		{desc: "v2 adds a data segment", version: kvVersionV2, expected: "secret/data/fabio/certs/example.com"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			paths := &kvPaths{cfg: testConfig(), version: test.version}
+
+			if got := paths.dataPath("certs", "example.com"); got != test.expected {
+				t.Errorf("dataPath() = %q, want %q", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestKVPathsWrap(t *testing.T) {
+	data := map[string]interface{}{"cert": "PEM"}
+
+	v1 := &kvPaths{cfg: testConfig(), version: kvVersionV1}
+	if got := v1.wrap(data); got["cert"] != "PEM" {
+		t.Errorf("v1 wrap() = %v, want data returned unchanged", got)
+	}
+
+	v2 := &kvPaths{cfg: testConfig(), version: kvVersionV2}
+	wrapped := v2.wrap(data)
+	inner, ok := wrapped["data"].(map[string]interface{})
+	if !ok || inner["cert"] != "PEM" {
+		t.Errorf("v2 wrap() = %v, want data nested under \"data\"", wrapped)
+	}
+}
+
+func TestKVPathsUnwrap(t *testing.T) {
+	t.Run("v1 returns the response data unchanged", func(t *testing.T) {
+		paths := &kvPaths{cfg: testConfig(), version: kvVersionV1}
+
+		resp := &api.Secret{Data: map[string]interface{}{"cert": "PEM"}}
+		data, err := paths.unwrap(resp)
+		if err != nil {
+			t.Fatalf("unwrap() error = %v", err)
+		}
+		if data["cert"] != "PEM" {
+			t.Errorf("unwrap() = %v, want %v", data, resp.Data)
+		}
+	})
+
+	t.Run("v2 strips the data envelope", func(t *testing.T) {
+		paths := &kvPaths{cfg: testConfig(), version: kvVersionV2}
+
+		resp := &api.Secret{Data: map[string]interface{}{
+			"data": map[string]interface{}{"cert": "PEM"},
+		}}
+		data, err := paths.unwrap(resp)
+		if err != nil {
+			t.Fatalf("unwrap() error = %v", err)
+		}
+		if data["cert"] != "PEM" {
+			t.Errorf("unwrap() = %v, want {\"cert\": \"PEM\"}", data)
+		}
+	})
+
+	t.Run("v2 errors on a malformed response", func(t *testing.T) {
+		paths := &kvPaths{cfg: testConfig(), version: kvVersionV2}
+
+		resp := &api.Secret{Data: map[string]interface{}{"cert": "PEM"}}
+		if _, err := paths.unwrap(resp); err == nil {
+			t.Error("unwrap() error = nil, want an error for a missing \"data\" key")
+		}
+	})
+}
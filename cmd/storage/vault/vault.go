@@ -0,0 +1,411 @@
+// Package vault implements a HashiCorp Vault storage backend.
+package vault
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"sync"
+
+	"github.com/go-acme/lego/certcrypto"
+	"github.com/go-acme/lego/certificate"
+	"github.com/go-acme/lego/cmd/storage"
+	"github.com/go-acme/lego/log"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	storage.Register("vault", New)
+}
+
+const (
+	segmentCerts      = "certs"
+	segmentJSON       = "json"
+	segmentAccount    = "account"
+	segmentPrivateKey = "private_key"
+)
+
+// Storage is the HashiCorp Vault implementation of storage.Storage.
+//
+// It understands both the KV v1 and KV v2 secrets engines: the path layout
+// and envelope used to address a given entry are resolved once, lazily,
+// against cfg (see Config and kvPaths).
+type Storage struct {
+	client *vaultClient
+	cfg    *Config
+
+	mu    sync.Mutex
+	paths *kvPaths
+}
+
+// New creates the Vault storage backend.
+// The client is configured from the environment (VAULT_ADDR, VAULT_TOKEN, ...),
+// optionally overridden by the `--vault-addr` flag; the KV layout is
+// configured via `--vault-mount`, `--vault-path-prefix` and `--vault-kv-version`.
+func New(ctx *cli.Context) (storage.Storage, error) {
+	cfg := NewConfig(ctx)
+
+	return &Storage{
+		client: newVaultClient(cfg.Address),
+		cfg:    cfg,
+	}, nil
+}
+
+// kvPaths returns the resolved path helper, detecting the mount's KV version
+// on first use and caching the result for the lifetime of the Storage.
+func (s *Storage) kvPaths() (*kvPaths, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paths != nil {
+		return s.paths, nil
+	}
+
+	c, err := s.client.Get()
+	if err != nil {
+		return nil, fmt.Errorf("vault: client: %w", err)
+	}
+
+	paths, err := newKVPaths(c, s.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s.paths = paths
+
+	return paths, nil
+}
+
+func (s *Storage) SaveResource(certRes *certificate.Resource) error {
+	c, err := s.client.Get()
+	if err != nil {
+		return fmt.Errorf("vault: client: %w", err)
+	}
+
+	paths, err := s.kvPaths()
+	if err != nil {
+		return err
+	}
+
+	domain := certRes.Domain
+
+	// We store the certificate, private key and issuer in a single entry, as
+	// web servers would not be able to work with a combined PEM file anyway.
+	_, err = c.Logical().Write(paths.dataPath(segmentCerts, domain), paths.wrap(map[string]interface{}{
+		"cert":   string(certRes.Certificate),
+		"key":    string(certRes.PrivateKey),
+		"issuer": string(certRes.IssuerCertificate),
+	}))
+	if err != nil {
+		return fmt.Errorf("vault: unable to save certificate for domain %s: %w", domain, err)
+	}
+
+	jsonBytes, err := json.MarshalIndent(certRes, "", "\t")
+	if err != nil {
+		return fmt.Errorf("vault: unable to marshal certificate resource for domain %s: %w", domain, err)
+	}
+
+	_, err = c.Logical().Write(paths.dataPath(segmentJSON, domain), paths.wrap(map[string]interface{}{
+		"data": string(jsonBytes),
+	}))
+	if err != nil {
+		return fmt.Errorf("vault: unable to save certificate resource for domain %s: %w", domain, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ReadResource(domain string) (certificate.Resource, error) {
+	c, err := s.client.Get()
+	if err != nil {
+		return certificate.Resource{}, fmt.Errorf("vault: client: %w", err)
+	}
+
+	paths, err := s.kvPaths()
+	if err != nil {
+		return certificate.Resource{}, err
+	}
+
+	resp, err := c.Logical().Read(paths.dataPath(segmentJSON, domain))
+	if err != nil {
+		return certificate.Resource{}, fmt.Errorf("vault: error while loading the meta data for domain %s: %w", domain, err)
+	}
+	if resp == nil {
+		return certificate.Resource{}, fmt.Errorf("vault: no meta data found for domain %s", domain)
+	}
+
+	d, err := paths.unwrap(resp)
+	if err != nil {
+		return certificate.Resource{}, fmt.Errorf("vault: error while loading the meta data for domain %s: %w", domain, err)
+	}
+
+	var resource certificate.Resource
+	if err = json.Unmarshal([]byte(d["data"].(string)), &resource); err != nil {
+		return certificate.Resource{}, fmt.Errorf("vault: error while unmarshaling the meta data for domain %s: %w", domain, err)
+	}
+
+	return resource, nil
+}
+
+func (s *Storage) ExistsFile(domain, extension string) bool {
+	if extension != ".json" {
+		content, err := s.ReadFile(domain, extension)
+		return err == nil && len(content) > 0
+	}
+
+	c, err := s.client.Get()
+	if err != nil {
+		return false
+	}
+
+	paths, err := s.kvPaths()
+	if err != nil {
+		return false
+	}
+
+	resp, err := c.Logical().Read(paths.dataPath(segmentJSON, domain))
+	return err == nil && resp != nil
+}
+
+func (s *Storage) ReadFile(domain, extension string) ([]byte, error) {
+	c, err := s.client.Get()
+	if err != nil {
+		return nil, fmt.Errorf("vault: client: %w", err)
+	}
+
+	paths, err := s.kvPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Logical().Read(paths.dataPath(segmentCerts, domain))
+	if err != nil {
+		return nil, fmt.Errorf("vault: error while loading the certificate entry for domain %s: %w", domain, err)
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("vault: no certificate entry found for domain %s", domain)
+	}
+
+	d, err := paths.unwrap(resp)
+	if err != nil {
+		return nil, fmt.Errorf("vault: error while loading the certificate entry for domain %s: %w", domain, err)
+	}
+
+	var field string
+	switch extension {
+	case ".crt":
+		field = "cert"
+	case ".key":
+		field = "key"
+	case ".issuer.crt":
+		field = "issuer"
+	default:
+		return nil, fmt.Errorf("vault: unsupported extension %q", extension)
+	}
+
+	content, ok := d[field].(string)
+	if !ok {
+		return nil, fmt.Errorf("vault: certificate entry for domain %s has no %q field", domain, field)
+	}
+
+	return []byte(content), nil
+}
+
+func (s *Storage) ReadCertificate(domain, extension string) ([]*x509.Certificate, error) {
+	content, err := s.ReadFile(domain, extension)
+	if err != nil {
+		return nil, err
+	}
+
+	return certcrypto.ParsePEMBundle(content)
+}
+
+func (s *Storage) WriteFile(domain, extension string, data []byte) error {
+	return fmt.Errorf("vault: WriteFile is not supported, use SaveResource instead")
+}
+
+// MoveToArchive retires the certificate and metadata entries for domain.
+// Against a KV v2 mount this is a soft delete: Vault keeps the prior
+// versions, recoverable with `vault kv undelete`. Against a KV v1 mount,
+// which has no versioning, the entries are copied under an "archive"
+// segment before being removed so the data isn't lost.
+func (s *Storage) MoveToArchive(domain string) error {
+	c, err := s.client.Get()
+	if err != nil {
+		return fmt.Errorf("vault: client: %w", err)
+	}
+
+	paths, err := s.kvPaths()
+	if err != nil {
+		return err
+	}
+
+	if paths.version == kvVersionV2 {
+		for _, segment := range []string{segmentCerts, segmentJSON} {
+			if _, err = c.Logical().Delete(paths.dataPath(segment, domain)); err != nil {
+				return fmt.Errorf("vault: unable to archive %s entry for domain %s: %w", segment, domain, err)
+			}
+		}
+
+		return nil
+	}
+
+	for _, segment := range []string{segmentCerts, segmentJSON} {
+		resp, errRead := c.Logical().Read(paths.dataPath(segment, domain))
+		if errRead != nil || resp == nil {
+			continue
+		}
+
+		archivePath := fmt.Sprintf("%s/%s/archive/%s/%s", s.cfg.MountPath, s.cfg.PathPrefix, segment, domain)
+		if _, err = c.Logical().Write(archivePath, resp.Data); err != nil {
+			return fmt.Errorf("vault: unable to archive %s entry for domain %s: %w", segment, domain, err)
+		}
+
+		if _, err = c.Logical().Delete(paths.dataPath(segment, domain)); err != nil {
+			return fmt.Errorf("vault: unable to remove %s entry for domain %s after archiving: %w", segment, domain, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) HasAccount(userID string) bool {
+	c, err := s.client.Get()
+	if err != nil {
+		return false
+	}
+
+	paths, err := s.kvPaths()
+	if err != nil {
+		return false
+	}
+
+	resp, err := c.Logical().Read(paths.dataPath(segmentAccount, userID))
+	return err == nil && resp != nil
+}
+
+func (s *Storage) SaveAccount(account *storage.Account) error {
+	c, err := s.client.Get()
+	if err != nil {
+		return fmt.Errorf("vault: client: %w", err)
+	}
+
+	paths, err := s.kvPaths()
+	if err != nil {
+		return err
+	}
+
+	jsonBytes, err := json.MarshalIndent(account, "", "\t")
+	if err != nil {
+		return fmt.Errorf("vault: unable to marshal account %s: %w", account.Email, err)
+	}
+
+	_, err = c.Logical().Write(paths.dataPath(segmentAccount, account.Email), paths.wrap(map[string]interface{}{
+		"data": string(jsonBytes),
+	}))
+
+	return err
+}
+
+func (s *Storage) LoadAccount(userID string, privateKey crypto.PrivateKey) (*storage.Account, error) {
+	c, err := s.client.Get()
+	if err != nil {
+		return nil, fmt.Errorf("vault: client: %w", err)
+	}
+
+	paths, err := s.kvPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Logical().Read(paths.dataPath(segmentAccount, userID))
+	if err != nil {
+		return nil, fmt.Errorf("vault: error while loading account %s: %w", userID, err)
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("vault: no account found for %s", userID)
+	}
+
+	d, err := paths.unwrap(resp)
+	if err != nil {
+		return nil, fmt.Errorf("vault: error while loading account %s: %w", userID, err)
+	}
+
+	var account storage.Account
+	if err = json.Unmarshal([]byte(d["data"].(string)), &account); err != nil {
+		return nil, fmt.Errorf("vault: could not parse account %s: %w", userID, err)
+	}
+
+	account.SetPrivateKey(privateKey)
+
+	return &account, nil
+}
+
+func (s *Storage) GetPrivateKey(userID string, keyType certcrypto.KeyType) (crypto.PrivateKey, error) {
+	c, err := s.client.Get()
+	if err != nil {
+		return nil, fmt.Errorf("vault: client: %w", err)
+	}
+
+	paths, err := s.kvPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Logical().Read(paths.dataPath(segmentPrivateKey, userID))
+	if err != nil {
+		return nil, fmt.Errorf("vault: unable to read private key for account %s: %w", userID, err)
+	}
+
+	if resp == nil {
+		log.Printf("No key found for account %s. Generating a %s key.", userID, keyType)
+
+		privateKey, errGen := certcrypto.GeneratePrivateKey(keyType)
+		if errGen != nil {
+			return nil, fmt.Errorf("vault: could not generate private key for account %s: %w", userID, errGen)
+		}
+
+		if errSave := s.SavePrivateKey(userID, privateKey); errSave != nil {
+			return nil, errSave
+		}
+
+		return privateKey, nil
+	}
+
+	d, err := paths.unwrap(resp)
+	if err != nil {
+		return nil, fmt.Errorf("vault: could not load private key for account %s: %w", userID, err)
+	}
+
+	privateKey, err := storage.ParsePEMPrivateKey([]byte(d["data"].(string)))
+	if err != nil {
+		return nil, fmt.Errorf("vault: could not load private key for account %s: %w", userID, err)
+	}
+
+	return privateKey, nil
+}
+
+// SavePrivateKey persists privateKey verbatim as userID's account key,
+// overwriting any key already stored for it.
+func (s *Storage) SavePrivateKey(userID string, privateKey crypto.PrivateKey) error {
+	c, err := s.client.Get()
+	if err != nil {
+		return fmt.Errorf("vault: client: %w", err)
+	}
+
+	paths, err := s.kvPaths()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Logical().Write(paths.dataPath(segmentPrivateKey, userID), paths.wrap(map[string]interface{}{
+		"data": string(pem.EncodeToMemory(certcrypto.PEMBlock(privateKey))),
+	}))
+	if err != nil {
+		return fmt.Errorf("vault: could not save private key for account %s: %w", userID, err)
+	}
+
+	return nil
+}